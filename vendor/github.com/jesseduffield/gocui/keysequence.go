@@ -0,0 +1,329 @@
+// Copyright 2020 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// DefaultSequenceTimeout is how long a SequenceMatcher holds a partially
+// matched KeySequence before giving up and replaying the buffered keys as
+// individual key events.
+const DefaultSequenceTimeout = 500 * time.Millisecond
+
+// keySequenceStep is one key press within a KeySequence, e.g. the `ctrl+x`
+// in `ctrl+x ctrl+s`.
+type keySequenceStep struct {
+	Mod Modifier
+	Key Key
+	Ch  rune
+}
+
+// KeySequence is an ordered list of key presses that must all be matched in
+// order, with no unrelated key in between, before its handler fires. It
+// unblocks emacs/vim-style chord bindings such as `g g` or `ctrl+x ctrl+s`
+// that the flat single-key SetKeybinding API can't express.
+type KeySequence []keySequenceStep
+
+// ParseKeySequence parses space-separated key notation such as
+// "ctrl+x ctrl+s", "alt+shift+f10" or the angle-bracket "<c-g><c-k>" form
+// into a KeySequence.
+func ParseKeySequence(s string) (KeySequence, error) {
+	tokens, err := splitSequenceTokens(s)
+	if err != nil {
+		return nil, fmt.Errorf("gocui: invalid key sequence %q: %w", s, err)
+	}
+	seq := make(KeySequence, 0, len(tokens))
+	for _, tok := range tokens {
+		step, err := parseKeySequenceStep(tok)
+		if err != nil {
+			return nil, fmt.Errorf("gocui: invalid key sequence %q: %w", s, err)
+		}
+		seq = append(seq, step)
+	}
+	if len(seq) == 0 {
+		return nil, fmt.Errorf("gocui: empty key sequence %q", s)
+	}
+	return seq, nil
+}
+
+// splitSequenceTokens splits both the space-separated ("ctrl+x ctrl+s") and
+// the angle-bracket ("<c-g><c-k>") notations into individual step tokens.
+func splitSequenceTokens(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "<") {
+		return strings.Fields(s), nil
+	}
+	var tokens []string
+	for len(s) > 0 {
+		if s[0] != '<' {
+			return nil, fmt.Errorf("expected '<', got %q", s)
+		}
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated '<' in %q", s)
+		}
+		tokens = append(tokens, s[1:end])
+		s = s[end+1:]
+	}
+	return tokens, nil
+}
+
+// parseKeySequenceStep parses a single step such as "ctrl+x", "alt+shift+f10"
+// or the short "c-g" form used inside angle brackets.
+func parseKeySequenceStep(tok string) (keySequenceStep, error) {
+	sep := "+"
+	if !strings.Contains(tok, "+") {
+		sep = "-"
+	}
+	parts := strings.Split(tok, sep)
+	name := parts[len(parts)-1]
+
+	var step keySequenceStep
+	for _, mod := range parts[:len(parts)-1] {
+		switch strings.ToLower(mod) {
+		case "c", "ctrl", "control":
+			step.Mod |= ModCtrl
+		case "a", "alt":
+			step.Mod |= ModAlt
+		case "s", "shift":
+			step.Mod |= ModShift
+		default:
+			return step, fmt.Errorf("unknown modifier %q", mod)
+		}
+	}
+
+	key, ch, err := parseKeyName(name)
+	if err != nil {
+		return step, err
+	}
+	// Fold Ctrl and Shift into the key or rune, as tcellPollEvent delivers them.
+	switch {
+	case step.Mod&ModCtrl != 0 && ch >= 'a' && ch <= 'z':
+		key, ch = Key(tcell.KeyCtrlA)+Key(ch-'a'), 0
+	case step.Mod == ModCtrl && key == Key(' '):
+		key = Key(tcell.KeyCtrlSpace)
+	case step.Mod&ModShift != 0 && key == KeyTab:
+		key = Key(tcell.KeyBacktab)
+	case step.Mod&ModShift != 0 && ch >= 'a' && ch <= 'z':
+		ch -= 'a' - 'A'
+	}
+	step.Mod &^= ModShift
+	if key != Key(tcell.KeyBacktab) {
+		step.Mod &^= ModCtrl
+	}
+	step.Key = key
+	step.Ch = ch
+	return step, nil
+}
+
+// parseKeyName resolves the final element of a step, e.g. "x", "f10" or
+// "space", to either a rune (for plain letters) or a named Key constant.
+func parseKeyName(name string) (Key, rune, error) {
+	if key, ok := namedKeys[strings.ToLower(name)]; ok {
+		return key, 0, nil
+	}
+	runes := []rune(name)
+	if len(runes) == 1 {
+		return 0, runes[0], nil
+	}
+	return 0, 0, fmt.Errorf("unknown key %q", name)
+}
+
+// namedKeys maps the lower-cased textual names accepted by ParseKeySequence
+// to their gocui Key constants.
+var namedKeys = map[string]Key{
+	"space":  Key(' '),
+	"enter":  KeyEnter,
+	"tab":    KeyTab,
+	"esc":    KeyEsc,
+	"escape": KeyEsc,
+	"f1":     KeyF1,
+	"f2":     KeyF2,
+	"f3":     KeyF3,
+	"f4":     KeyF4,
+	"f5":     KeyF5,
+	"f6":     KeyF6,
+	"f7":     KeyF7,
+	"f8":     KeyF8,
+	"f9":     KeyF9,
+	"f10":    KeyF10,
+	"f11":    KeyF11,
+	"f12":    KeyF12,
+}
+
+// sequenceBinding pairs a parsed KeySequence with the handler that fires
+// once it's matched in full.
+type sequenceBinding struct {
+	seq     KeySequence
+	handler func() error
+}
+
+// SequenceMatcher maintains the prefix-match state needed to resolve
+// chorded key sequences registered via ParseKeySequence. pollEvent feeds it
+// every key event ahead of returning that event to its caller: while a
+// prefix is pending, HandleKey reports the key as consumed so it isn't
+// delivered as an ordinary key event until the sequence either completes or
+// times out.
+type SequenceMatcher struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	binds   []sequenceBinding
+	pending []keySequenceStep
+	timer   *time.Timer
+}
+
+// defaultSequenceMatcher is the matcher pollEvent feeds every key event
+// through, so bindings registered via SetKeybindingSequence actually fire.
+var defaultSequenceMatcher = NewSequenceMatcher(0)
+
+// SetKeybindingSequence registers handler to fire when seq is typed in
+// full, with no unrelated key in between. It's the chorded counterpart to
+// SetKeybinding for bindings like "g g" or "ctrl+x ctrl+s" that the flat
+// single-key API can't express.
+func SetKeybindingSequence(seq KeySequence, handler func() error) {
+	defaultSequenceMatcher.Bind(seq, handler)
+}
+
+// NewSequenceMatcher creates a matcher that replays buffered keys as plain
+// key events if a sequence isn't completed within timeout. A timeout of 0
+// uses DefaultSequenceTimeout.
+func NewSequenceMatcher(timeout time.Duration) *SequenceMatcher {
+	if timeout <= 0 {
+		timeout = DefaultSequenceTimeout
+	}
+	return &SequenceMatcher{timeout: timeout}
+}
+
+// Bind registers handler to fire once seq is matched in full. Bind is not
+// safe to call concurrently with HandleKey.
+func (m *SequenceMatcher) Bind(seq KeySequence, handler func() error) {
+	m.binds = append(m.binds, sequenceBinding{seq: seq, handler: handler})
+}
+
+// HandleKey feeds one key press into the matcher. It returns true if the
+// key was consumed as part of a (possibly still partial) sequence, in which
+// case the caller shouldn't deliver it as an ordinary key event. If the key
+// breaks a pending prefix, HandleKey queues the buffered keys onto
+// replayQueue (they happened before this one) and returns false so the
+// caller still delivers this key normally.
+func (m *SequenceMatcher) HandleKey(mod Modifier, key Key, ch rune) (bool, error) {
+	step := keySequenceStep{Mod: mod, Key: key, Ch: ch}
+
+	m.mu.Lock()
+	candidate := append(append([]keySequenceStep{}, m.pending...), step)
+	m.mu.Unlock()
+
+	var exact *sequenceBinding
+	hasPrefix := false
+	hasLonger := false
+	for i := range m.binds {
+		b := &m.binds[i]
+		if len(b.seq) < len(candidate) || !sequenceHasPrefix(b.seq, candidate) {
+			continue
+		}
+		hasPrefix = true
+		if len(b.seq) == len(candidate) {
+			exact = b
+		} else {
+			hasLonger = true
+		}
+	}
+
+	if !hasPrefix {
+		enqueueSteps(m.clearPending())
+		return false, nil
+	}
+
+	// An exact match that no other binding still extends (e.g. "g" when
+	// only "g" is bound) fires right away. If a longer binding shares the
+	// same prefix (e.g. both "g" and "g g" are bound), wait for the
+	// timeout/next key like any other still-ambiguous prefix, so the
+	// longer sequence remains reachable.
+	if exact != nil && !hasLonger {
+		m.clearPending()
+		return true, exact.handler()
+	}
+
+	m.mu.Lock()
+	m.pending = candidate
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.timer = time.AfterFunc(m.timeout, func() {
+		buffered := m.clearPending()
+		if len(buffered) == 0 {
+			return
+		}
+		if exact != nil {
+			_ = exact.handler()
+			return
+		}
+		m.replay(buffered)
+	})
+	m.mu.Unlock()
+	return true, nil
+}
+
+// replay re-injects buffered steps as tcell key events once a sequence
+// times out without completing, via Screen.PostEvent so a pollEvent call
+// blocked waiting on the next real key wakes up immediately instead of only
+// seeing these once another key arrives. This only reaches the tcell
+// backend; under SetRenderer(RendererVaxis) a timed-out chord is replayed
+// the same way HandleKey's synchronous prefix-break path above does it, as
+// soon as the next real key comes in.
+func (m *SequenceMatcher) replay(steps []keySequenceStep) {
+	for _, s := range steps {
+		k := tcell.Key(s.Key)
+		ch := s.Ch
+		if s.Key == 0 {
+			k = tcell.KeyRune
+		}
+		if Screen != nil {
+			_ = Screen.PostEvent(tcell.NewEventKey(k, ch, tcell.ModMask(s.Mod)))
+		}
+	}
+}
+
+// enqueueSteps appends steps to replayQueue as ordinary key events. It's
+// called from HandleKey's synchronous path, on the same goroutine as
+// pollEvent, so appending directly is safe.
+func enqueueSteps(steps []keySequenceStep) {
+	for _, s := range steps {
+		replayQueue = append(replayQueue, GocuiEvent{Type: eventKey, Mod: s.Mod, Key: s.Key, Ch: s.Ch})
+	}
+}
+
+// clearPending atomically stops any pending timer and returns/clears the
+// buffered steps. It's called both from HandleKey and from the timer's own
+// goroutine, so m.pending and m.timer are always accessed under m.mu.
+func (m *SequenceMatcher) clearPending() []keySequenceStep {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	buffered := m.pending
+	m.pending = nil
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	return buffered
+}
+
+// sequenceHasPrefix reports whether prefix's steps match seq's leading
+// steps exactly. The caller has already checked len(seq) >= len(prefix).
+func sequenceHasPrefix(seq, prefix []keySequenceStep) bool {
+	for i, step := range prefix {
+		if seq[i] != step {
+			return false
+		}
+	}
+	return true
+}
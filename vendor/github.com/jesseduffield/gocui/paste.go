@@ -0,0 +1,77 @@
+// Copyright 2020 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "github.com/gdamore/tcell/v2"
+
+// PasteHandler, if set, receives the full text of a completed bracketed
+// paste so it can be delivered atomically instead of replayed as individual
+// key events. It's nil by default, which is the right behaviour for any
+// caller that hasn't opted in: pasted text then comes out of pollEvent
+// exactly as it would have without EnableBracketedPaste, one key event per
+// rune, so existing keybindings and editors keep working unchanged.
+var PasteHandler func(paste string) error
+
+// consumePaste is what tcellPollEvent calls once a bracketed paste
+// finishes. With PasteHandler set it delivers the whole string in one call;
+// otherwise it queues a key event per rune onto replayQueue and returns the
+// first one immediately rather than losing a poll cycle.
+func consumePaste(paste string) GocuiEvent {
+	if PasteHandler != nil {
+		if err := PasteHandler(paste); err != nil {
+			return GocuiEvent{Type: eventError, Err: err}
+		}
+		return GocuiEvent{Type: eventNone}
+	}
+
+	var first GocuiEvent
+	hasFirst := false
+	for _, ch := range paste {
+		ev := pasteRuneEvent(ch)
+		if !hasFirst {
+			first, hasFirst = ev, true
+			continue
+		}
+		replayQueue = append(replayQueue, ev)
+	}
+	if !hasFirst {
+		return GocuiEvent{Type: eventNone}
+	}
+	return first
+}
+
+// pasteRune returns the rune a buffered key event contributes to a paste,
+// translating the control keys tcell reports with Rune() == 0 to the
+// character they stand for so pasted whitespace survives intact.
+func pasteRune(tev *tcell.EventKey) rune {
+	switch tev.Key() {
+	case tcell.KeyEnter:
+		return '\n'
+	case tcell.KeyTab:
+		return '\t'
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		return '\b'
+	default:
+		return tev.Rune()
+	}
+}
+
+// pasteRuneEvent mirrors tcellPollEvent's own rune-to-key special casing,
+// and turns the characters pasteRune produced for Enter, Tab and Backspace
+// back into those keys, so a replayed paste rune behaves like the key event
+// it's standing in for.
+func pasteRuneEvent(ch rune) GocuiEvent {
+	switch ch {
+	case ' ':
+		return GocuiEvent{Type: eventKey, Key: 32}
+	case '\n':
+		return GocuiEvent{Type: eventKey, Key: Key(tcell.KeyEnter)}
+	case '\t':
+		return GocuiEvent{Type: eventKey, Key: Key(tcell.KeyTab)}
+	case '\b':
+		return GocuiEvent{Type: eventKey, Key: Key(tcell.KeyBackspace)}
+	}
+	return GocuiEvent{Type: eventKey, Ch: ch}
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "fmt"
+
+// RendererKind selects which terminal backend Gui drives.
+type RendererKind int
+
+const (
+	RendererTcell RendererKind = iota
+	RendererVaxis
+)
+
+// Renderer is the terminal I/O surface gocui drives, factored out of the
+// tcell-specific globals so an alternative backend can be swapped in. It's
+// intentionally the small surface gocui has always needed from
+// tcell.Screen. tcellRenderer is the long-standing implementation;
+// vaxisRenderer (built with the `vaxis` build tag) is an opt-in alternative
+// with Kitty keyboard/graphics and native Sixel support that tcell lacks.
+type Renderer interface {
+	Init() error
+	Fini()
+	PollEvent() GocuiEvent
+	SetContent(x, y int, ch rune, fg, bg Attribute, omode OutputMode)
+	Show()
+	Sync()
+	Size() (int, int)
+	EnablePaste(enable bool)
+	EnableMouse(enable bool)
+}
+
+// activeRenderer is the backend pollEvent, tcellSetCell and friends are
+// currently driving. It defaults to a tcellRenderer wrapping the package's
+// existing global Screen, so existing callers of tcellInit are unaffected.
+var activeRenderer Renderer = &tcellRenderer{}
+
+// SetRenderer switches the active rendering backend. Call it before Init so
+// the chosen backend owns Screen from the start.
+func SetRenderer(kind RendererKind) error {
+	r, err := newRenderer(kind)
+	if err != nil {
+		return err
+	}
+	activeRenderer = r
+	return nil
+}
+
+func newRenderer(kind RendererKind) (Renderer, error) {
+	switch kind {
+	case RendererTcell:
+		return &tcellRenderer{}, nil
+	case RendererVaxis:
+		return newVaxisRenderer()
+	default:
+		return nil, fmt.Errorf("gocui: unknown renderer kind %d", kind)
+	}
+}
+
+// tcellRenderer adapts the package's existing tcell-backed globals (Screen,
+// tcellInit, tcellDrawCell, tcellPollEvent) to the Renderer interface.
+type tcellRenderer struct{}
+
+func (r *tcellRenderer) Init() error {
+	return tcellInit()
+}
+
+func (r *tcellRenderer) Fini() {
+	Screen.Fini()
+}
+
+func (r *tcellRenderer) PollEvent() GocuiEvent {
+	return tcellPollEvent()
+}
+
+func (r *tcellRenderer) Show() {
+	Screen.Show()
+}
+
+func (r *tcellRenderer) Sync() {
+	Screen.Sync()
+}
+
+func (r *tcellRenderer) Size() (int, int) {
+	return Screen.Size()
+}
+
+func (r *tcellRenderer) EnablePaste(enable bool) {
+	if enable {
+		Screen.EnablePaste()
+	} else {
+		Screen.DisablePaste()
+	}
+}
+
+func (r *tcellRenderer) SetContent(x, y int, ch rune, fg, bg Attribute, omode OutputMode) {
+	tcellDrawCell(x, y, ch, fg, bg, omode)
+}
+
+func (r *tcellRenderer) EnableMouse(enable bool) {
+	if enable {
+		Screen.EnableMouse()
+	} else {
+		Screen.DisableMouse()
+	}
+}
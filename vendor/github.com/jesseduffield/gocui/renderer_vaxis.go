@@ -0,0 +1,164 @@
+// Copyright 2020 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build vaxis
+
+package gocui
+
+// Building with -tags vaxis additionally requires adding
+// git.sr.ht/~rockorager/vaxis to go.mod/go.sum and vendoring it alongside
+// the rest of this tree's dependencies; this checkout predates both, so
+// that's a prerequisite step for whoever first enables the tag, not
+// something this file can do on its own.
+import (
+	vaxis "git.sr.ht/~rockorager/vaxis"
+)
+
+// vaxisRenderer implements Renderer on top of vaxis, unlocking the Kitty
+// keyboard protocol (unambiguous ctrl+shift+letter and key-release events),
+// inline Kitty graphics for image previews, and native Sixel support that
+// tcell doesn't have. It's opt-in via SetRenderer(RendererVaxis) and this
+// whole file is excluded unless built with the `vaxis` tag.
+type vaxisRenderer struct {
+	vx *vaxis.Vaxis
+}
+
+func newVaxisRenderer() (Renderer, error) {
+	vx, err := vaxis.New(vaxis.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return &vaxisRenderer{vx: vx}, nil
+}
+
+func (r *vaxisRenderer) Init() error {
+	return nil
+}
+
+func (r *vaxisRenderer) Fini() {
+	r.vx.Close()
+}
+
+func (r *vaxisRenderer) PollEvent() GocuiEvent {
+	switch ev := r.vx.PollEvent().(type) {
+	case vaxis.Key:
+		var ch rune
+		if r := []rune(ev.Text); len(r) > 0 {
+			ch = r[0]
+		}
+		return GocuiEvent{
+			Type: eventKey,
+			Key:  Key(ev.Keycode),
+			Ch:   ch,
+			Mod:  vaxisModifier(ev.Modifiers),
+		}
+	case vaxis.Resize:
+		return GocuiEvent{Type: eventResize, Width: ev.Cols, Height: ev.Rows}
+	default:
+		return GocuiEvent{Type: eventNone}
+	}
+}
+
+func (r *vaxisRenderer) SetContent(x, y int, ch rune, fg, bg Attribute, omode OutputMode) {
+	win := r.vx.Window()
+	win.SetCell(x, y, vaxis.Cell{
+		Character: vaxis.Character{Grapheme: string(ch)},
+		Style:     vaxisStyleFor(fg, bg, omode),
+	})
+}
+
+func (r *vaxisRenderer) Show() {
+	r.vx.Render()
+}
+
+func (r *vaxisRenderer) Sync() {
+	r.vx.Render()
+}
+
+func (r *vaxisRenderer) Size() (int, int) {
+	win := r.vx.Window()
+	return win.Width(), win.Height()
+}
+
+func (r *vaxisRenderer) EnablePaste(enable bool) {
+	// vaxis reports bracketed paste natively; nothing to toggle.
+}
+
+func (r *vaxisRenderer) EnableMouse(enable bool) {
+	if enable {
+		r.vx.EnableMouse()
+	} else {
+		r.vx.DisableMouse()
+	}
+}
+
+func vaxisModifier(m vaxis.ModifierMask) Modifier {
+	var mod Modifier
+	if m&vaxis.ModCtrl != 0 {
+		mod |= ModCtrl
+	}
+	if m&vaxis.ModAlt != 0 {
+		mod |= ModAlt
+	}
+	if m&vaxis.ModShift != 0 {
+		mod |= ModShift
+	}
+	return mod
+}
+
+// vaxisStyleFor mirrors getTcellStyle/tcellColorFor for the vaxis backend,
+// decoding true-color attributes and bold/underline/etc. font effects into
+// a vaxis.Style so SetContent doesn't silently drop fg, bg and omode.
+func vaxisStyleFor(fg, bg Attribute, omode OutputMode) vaxis.Style {
+	var st vaxis.Style
+	if fg != ColorDefault {
+		st.Foreground = vaxisColorFor(fg, omode)
+		st = setVaxisFontEffectStyle(st, fg)
+	}
+	if bg != ColorDefault {
+		st.Background = vaxisColorFor(bg, omode)
+		st = setVaxisFontEffectStyle(st, bg)
+	}
+	return st
+}
+
+// vaxisColorFor resolves attr to a vaxis.Color, decoding a packed
+// true-color RGB triple directly when omode is OutputTrue.
+func vaxisColorFor(attr Attribute, omode OutputMode) vaxis.Color {
+	if omode == OutputTrue {
+		if r, g, b, ok := trueColorRGB(attr); ok {
+			return vaxis.RGBColor(r, g, b)
+		}
+	}
+	return vaxis.IndexColor(uint8(attr))
+}
+
+// setVaxisFontEffectStyle applies the same AttrBold/AttrUnderline/etc. font
+// effects setTcellFontEffectStyle applies for the tcell backend. attr's
+// AttrBold/etc. bits sit below trueColorShift, so they survive untouched
+// whether or not attr also carries a packed true-color RGB payload.
+func setVaxisFontEffectStyle(st vaxis.Style, attr Attribute) vaxis.Style {
+	if attr&AttrBold != 0 {
+		st.Attribute |= vaxis.AttrBold
+	}
+	if attr&AttrUnderline != 0 {
+		st.Attribute |= vaxis.AttrUnderline
+	}
+	if attr&AttrReverse != 0 {
+		st.Attribute |= vaxis.AttrReverse
+	}
+	if attr&AttrBlink != 0 {
+		st.Attribute |= vaxis.AttrBlink
+	}
+	if attr&AttrDim != 0 {
+		st.Attribute |= vaxis.AttrDim
+	}
+	if attr&AttrItalic != 0 {
+		st.Attribute |= vaxis.AttrItalic
+	}
+	if attr&AttrStrikeThrough != 0 {
+		st.Attribute |= vaxis.AttrStrikeThrough
+	}
+	return st
+}
@@ -0,0 +1,16 @@
+// Copyright 2020 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !vaxis
+
+package gocui
+
+import "fmt"
+
+// newVaxisRenderer is stubbed out unless built with the `vaxis` tag, so
+// SetRenderer(RendererVaxis) fails loudly instead of silently falling back
+// to tcell.
+func newVaxisRenderer() (Renderer, error) {
+	return nil, fmt.Errorf("gocui: built without the 'vaxis' build tag")
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// InitSimulation initializes Screen with a tcell.SimulationScreen of the
+// given size instead of a real terminal. It is a drop-in alternative to
+// tcellInit for headless `go test` runs that need to drive views and
+// keybindings without a pty.
+func InitSimulation(cols, rows int) error {
+	s := tcell.NewSimulationScreen("")
+	if e := s.Init(); e != nil {
+		return e
+	}
+	s.SetSize(cols, rows)
+	Screen = s
+	return nil
+}
+
+// simulationScreen returns Screen as a tcell.SimulationScreen. It panics if
+// Screen wasn't set up via InitSimulation, since the Simulate* helpers below
+// only make sense against a simulation screen.
+func simulationScreen() tcell.SimulationScreen {
+	return Screen.(tcell.SimulationScreen)
+}
+
+// SimulateKeyEvent injects a key press into the simulation screen as if it
+// had come from the terminal.
+func SimulateKeyEvent(key Key, ch rune, mod Modifier) {
+	simulationScreen().InjectKey(tcell.Key(key), ch, tcell.ModMask(mod))
+}
+
+// SimulateRuneEvent injects a single printable rune, for convenience when
+// typing out strings in tests.
+func SimulateRuneEvent(ch rune) {
+	simulationScreen().InjectKey(tcell.KeyRune, ch, tcell.ModNone)
+}
+
+// SimulateMouseEvent injects a mouse click, release or drag at (x, y).
+func SimulateMouseEvent(x, y int, button tcell.ButtonMask, mod Modifier) {
+	simulationScreen().InjectMouse(x, y, button, tcell.ModMask(mod))
+}
+
+// SimulateResize resizes the simulation screen and queues the resulting
+// resize event, mirroring what a real terminal resize would do.
+func SimulateResize(cols, rows int) {
+	simulationScreen().SetSize(cols, rows)
+}
+
+// InjectKeyEvent is a GocuiEvent-shaped convenience wrapper around
+// SimulateKeyEvent, for tests that already build GocuiEvents by hand.
+func InjectKeyEvent(ev GocuiEvent) {
+	key := ev.Key
+	if key == 0 && ev.Ch != 0 {
+		// a GocuiEvent carries a plain rune as Key 0 plus Ch
+		key = Key(tcell.KeyRune)
+	}
+	SimulateKeyEvent(key, ev.Ch, ev.Mod)
+}
+
+// RenderedScreen renders the current contents of the simulation screen as a
+// plain string, one line per row, so tests can assert on what would have
+// been drawn to the terminal.
+func RenderedScreen() string {
+	cells, cols, rows := simulationScreen().GetContents()
+	var b strings.Builder
+	for y := 0; y < rows; y++ {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		for x := 0; x < cols; x++ {
+			c := cells[y*cols+x]
+			if len(c.Runes) == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteRune(c.Runes[0])
+			}
+		}
+	}
+	return b.String()
+}
+
+// RenderedCell returns the rune and style currently drawn at (x, y) on the
+// simulation screen, for tests that need to assert on colors/attributes
+// rather than just text.
+func RenderedCell(x, y int) (rune, tcell.Style) {
+	cells, cols, _ := simulationScreen().GetContents()
+	c := cells[y*cols+x]
+	ch := rune(' ')
+	if len(c.Runes) > 0 {
+		ch = c.Runes[0]
+	}
+	return ch, c.Style
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInitSimulationRenderedScreen(t *testing.T) {
+	if err := InitSimulation(10, 3); err != nil {
+		t.Fatalf("InitSimulation: %v", err)
+	}
+	defer Screen.Fini()
+
+	tcellSetCell(0, 0, 'h', ColorDefault, ColorDefault, OutputNormal)
+	tcellSetCell(1, 0, 'i', ColorDefault, ColorDefault, OutputNormal)
+	Screen.Show()
+	Screen.Sync()
+
+	lines := strings.Split(RenderedScreen(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 rows, got %d: %q", len(lines), lines)
+	}
+	if !strings.HasPrefix(lines[0], "hi") {
+		t.Fatalf("expected first row to start with %q, got %q", "hi", lines[0])
+	}
+}
+
+func TestSimulateRuneEvent(t *testing.T) {
+	if err := InitSimulation(10, 3); err != nil {
+		t.Fatalf("InitSimulation: %v", err)
+	}
+	defer Screen.Fini()
+
+	SimulateRuneEvent('x')
+
+	ev := pollEvent()
+	if ev.Type != eventKey || ev.Ch != 'x' {
+		t.Fatalf("expected rune key event for 'x', got %+v", ev)
+	}
+}
+
+func TestSimulateResize(t *testing.T) {
+	if err := InitSimulation(10, 3); err != nil {
+		t.Fatalf("InitSimulation: %v", err)
+	}
+	defer Screen.Fini()
+
+	SimulateResize(20, 5)
+
+	ev := pollEvent()
+	if ev.Type != eventResize || ev.Width != 20 || ev.Height != 5 {
+		t.Fatalf("expected resize event to 20x5, got %+v", ev)
+	}
+}
@@ -24,8 +24,16 @@ func tcellInit() error {
 }
 
 // tcellSetCell sets the character cell at a given location to the given
-// content (rune) and attributes using provided OutputMode
+// content (rune) and attributes using provided OutputMode. It goes through
+// activeRenderer so that SetRenderer(RendererVaxis) actually takes effect
+// for callers that still draw through this package-level helper.
 func tcellSetCell(x, y int, ch rune, fg, bg Attribute, omode OutputMode) {
+	activeRenderer.SetContent(x, y, ch, fg, bg, omode)
+}
+
+// tcellDrawCell is the tcell-specific implementation backing
+// tcellRenderer.SetContent.
+func tcellDrawCell(x, y int, ch rune, fg, bg Attribute, omode OutputMode) {
 	st := getTcellStyle(fg, bg, omode)
 	Screen.SetContent(x, y, ch, nil, st)
 }
@@ -36,11 +44,11 @@ func getTcellStyle(fg, bg Attribute, omode OutputMode) tcell.Style {
 
 	// extract colors and attributes
 	if fg != ColorDefault {
-		st = st.Foreground(getTcellColor(fg, omode))
+		st = st.Foreground(tcellColorFor(fg, omode))
 		st = setTcellFontEffectStyle(st, fg)
 	}
 	if bg != ColorDefault {
-		st = st.Background(getTcellColor(bg, omode))
+		st = st.Background(tcellColorFor(bg, omode))
 		st = setTcellFontEffectStyle(st, bg)
 	}
 
@@ -81,6 +89,7 @@ type gocuiEventType uint8
 //  The 'MouseX' and 'MouseY' fields are valid if 'Type' is 'eventMouse'.
 //  The 'Width' and 'Height' fields are valid if 'Type' is 'eventResize'.
 //  The 'Err' field is valid if 'Type' is 'eventError'.
+//  The 'Paste' field is valid if 'Type' is 'eventPaste'.
 type GocuiEvent struct {
 	Type   gocuiEventType
 	Mod    Modifier
@@ -92,6 +101,7 @@ type GocuiEvent struct {
 	MouseX int
 	MouseY int
 	N      int
+	Paste  string
 }
 
 // Event types.
@@ -103,6 +113,7 @@ const (
 	eventInterrupt
 	eventError
 	eventRaw
+	eventPaste
 )
 
 const (
@@ -117,10 +128,80 @@ var (
 	dragState    int              = NOT_DRAGGING
 	lastX        int              = 0
 	lastY        int              = 0
+	pasting      bool
+	pasteBuf     []rune
 )
 
-// pollEvent get tcell.Event and transform it into gocuiEvent
+// replayQueue holds GocuiEvents pollEvent has already decided to emit but
+// hasn't returned yet: keys a SequenceMatcher gave up on once it learned
+// they didn't belong to a chord after all. pollEvent drains it before
+// asking activeRenderer for a new event.
+var replayQueue []GocuiEvent
+
+// nextReplayedEvent pops the next queued event, if any.
+func nextReplayedEvent() (GocuiEvent, bool) {
+	if len(replayQueue) == 0 {
+		return GocuiEvent{}, false
+	}
+	ev := replayQueue[0]
+	replayQueue = replayQueue[1:]
+	return ev, true
+}
+
+// EnableBracketedPaste turns terminal bracketed-paste mode on or off. While
+// enabled, pollEvent buffers the runes of a paste until it ends; see
+// consumePaste for how that buffered paste is then delivered (whole to
+// PasteHandler if one is set, otherwise as one key event per rune). It
+// goes through activeRenderer, like
+// tcellSetCell, so it still does the right thing under
+// SetRenderer(RendererVaxis) instead of reaching for the tcell-only Screen,
+// which is nil until tcellInit has run.
+func EnableBracketedPaste(enable bool) {
+	activeRenderer.EnablePaste(enable)
+}
+
+// pollEvent returns the next input event, decoded from whichever backend
+// activeRenderer is currently driving (tcell by default, or vaxis once
+// SetRenderer(RendererVaxis) has been called). Before asking the backend
+// for a new event, it first drains any key events a previous call's
+// SequenceMatcher.HandleKey gave up on, and it routes every key event
+// through defaultSequenceMatcher so chorded bindings registered via
+// SetKeybindingSequence actually get a chance to fire.
 func pollEvent() GocuiEvent {
+	for {
+		if ev, ok := nextReplayedEvent(); ok {
+			return ev
+		}
+		ev := activeRenderer.PollEvent()
+		if ev.Type != eventKey {
+			return ev
+		}
+		queued := len(replayQueue)
+		consumed, err := defaultSequenceMatcher.HandleKey(ev.Mod, ev.Key, ev.Ch)
+		if err != nil {
+			return GocuiEvent{Type: eventError, Err: err}
+		}
+		if !consumed {
+			// HandleKey may have just queued keys it was buffering for a
+			// prefix that turned out to be a dead end; they happened
+			// before ev, so deliver them first and queue ev behind them,
+			// ahead of anything already queued behind ev (the rest of a
+			// paste).
+			if flushed := replayQueue[queued:]; len(flushed) > 0 {
+				rest := replayQueue[:queued]
+				replayQueue = append(append(append([]GocuiEvent{}, flushed[1:]...), ev), rest...)
+				return flushed[0]
+			}
+			return ev
+		}
+		// The key was absorbed into a (possibly now-fired) sequence; it
+		// isn't delivered on its own, so go back for the next event.
+	}
+}
+
+// tcellPollEvent gets a tcell.Event and transforms it into a GocuiEvent. It
+// backs tcellRenderer.PollEvent.
+func tcellPollEvent() GocuiEvent {
 	tev := Screen.PollEvent()
 	switch tev := tev.(type) {
 	case *tcell.EventInterrupt:
@@ -128,7 +209,21 @@ func pollEvent() GocuiEvent {
 	case *tcell.EventResize:
 		w, h := tev.Size()
 		return GocuiEvent{Type: eventResize, Width: w, Height: h}
+	case *tcell.EventPaste:
+		if tev.Start() {
+			pasting = true
+			pasteBuf = pasteBuf[:0]
+			return GocuiEvent{Type: eventNone}
+		}
+		pasting = false
+		s := string(pasteBuf)
+		pasteBuf = nil
+		return consumePaste(s)
 	case *tcell.EventKey:
+		if pasting {
+			pasteBuf = append(pasteBuf, pasteRune(tev))
+			return GocuiEvent{Type: eventNone}
+		}
 		k := tev.Key()
 		ch := rune(0)
 		if k == tcell.KeyRune {
@@ -141,16 +236,28 @@ func pollEvent() GocuiEvent {
 			}
 		}
 		mod := tev.Modifiers()
+		switch {
 		// remove control modifier and setup special handling of ctrl+spacebar, etc.
-		if mod == tcell.ModCtrl && k == 32 {
+		case mod == tcell.ModCtrl && k == 32:
 			mod = 0
 			ch = rune(0)
 			k = tcell.KeyCtrlSpace
-		} else if mod == tcell.ModCtrl || mod == tcell.ModShift {
-			// remove Ctrl or Shift if specified
-			// - shift - will be translated to the final code of rune
+		case k == tcell.KeyTab && mod&tcell.ModShift != 0:
+			// some terminals report shift-tab as Tab+ModShift rather than a
+			// dedicated Backtab key; normalise both to KeyBacktab
+			k = tcell.KeyBacktab
+			mod &^= tcell.ModShift
+		case k == tcell.KeyBacktab:
+			// already a dedicated Backtab key; strip Shift the same way the
+			// case above does, so ctrl+shift+tab carries the same modifier
+			// regardless of which of the two forms the terminal reports it as
+			mod &^= tcell.ModShift
+		default:
+			// Ctrl and Shift are already folded into the key/rune by tcell:
+			// - shift - is translated to the final code of rune
 			// - ctrl  - is translated in the key
-			mod = 0
+			// Alt and Meta aren't folded in anywhere, so they're kept.
+			mod &^= tcell.ModCtrl | tcell.ModShift
 		}
 		return GocuiEvent{
 			Type: eventKey,
@@ -190,8 +297,14 @@ func pollEvent() GocuiEvent {
 				lastY = y
 			case tcell.ButtonSecondary:
 				mouseKey = MouseRight
+				dragState = MAYBE_DRAGGING
+				lastX = x
+				lastY = y
 			case tcell.ButtonMiddle:
 				mouseKey = MouseMiddle
+				dragState = MAYBE_DRAGGING
+				lastX = x
+				lastY = y
 			}
 		}
 
@@ -199,10 +312,8 @@ func pollEvent() GocuiEvent {
 		case tcell.ButtonNone:
 			if lastMouseKey != tcell.ButtonNone {
 				switch lastMouseKey {
-				case tcell.ButtonPrimary:
+				case tcell.ButtonPrimary, tcell.ButtonSecondary, tcell.ButtonMiddle:
 					dragState = NOT_DRAGGING
-				case tcell.ButtonSecondary:
-				case tcell.ButtonMiddle:
 				}
 				mouseMod = Modifier(lastMouseMod)
 				lastMouseMod = tcell.ModNone
@@ -220,7 +331,14 @@ func pollEvent() GocuiEvent {
 			}
 		case DRAGGING:
 			mouseMod = ModMotion
-			mouseKey = MouseLeft
+			switch lastMouseKey {
+			case tcell.ButtonSecondary:
+				mouseKey = MouseRight
+			case tcell.ButtonMiddle:
+				mouseKey = MouseMiddle
+			default:
+				mouseKey = MouseLeft
+			}
 		}
 
 		return GocuiEvent{
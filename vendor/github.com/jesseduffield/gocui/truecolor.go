@@ -0,0 +1,60 @@
+// Copyright 2020 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "github.com/gdamore/tcell/v2"
+
+// OutputTrue enables 24-bit RGB output via NewRGBAttribute, bypassing the
+// 256-color palette entirely. It requires a terminal that advertises true
+// color support (tcell v2.6+ already detects COLORTERM=truecolor).
+//
+// It's declared as the next value after OutputGrayscale rather than a
+// literal so it can never collide with an existing OutputMode.
+const OutputTrue = OutputGrayscale + 1
+
+// trueColorFlag marks an Attribute as carrying a packed 24-bit RGB triple
+// rather than a palette index. It lives in the top bit of Attribute, well
+// above the existing palette-index and AttrBold/AttrUnderline/etc. bits, so
+// a true-color Attribute still tests true for whichever style flags are
+// ORed into it.
+const trueColorFlag = Attribute(1) << 63
+
+// trueColorShift is where the packed RGB triple starts: above the existing
+// low bits (palette index plus the AttrBold/AttrUnderline/etc. style flags)
+// so the two encodings never overlap.
+const trueColorShift = 24
+
+// NewRGBAttribute packs an exact RGB triple into an Attribute so it can be
+// used as a view's foreground/background color when OutputTrue is active,
+// rather than being quantized to the 256-color palette. Any AttrBold,
+// AttrUnderline, etc. bits can still be ORed into the result, since they
+// occupy lower bits than trueColorShift.
+func NewRGBAttribute(r, g, b uint8) Attribute {
+	return trueColorFlag |
+		Attribute(r)<<(trueColorShift+16) |
+		Attribute(g)<<(trueColorShift+8) |
+		Attribute(b)<<trueColorShift
+}
+
+// trueColorRGB extracts the RGB triple packed into attr by NewRGBAttribute.
+// ok is false if attr doesn't carry the true-color flag.
+func trueColorRGB(attr Attribute) (r, g, b uint8, ok bool) {
+	if attr&trueColorFlag == 0 {
+		return 0, 0, 0, false
+	}
+	return uint8(attr >> (trueColorShift + 16)), uint8(attr >> (trueColorShift + 8)), uint8(attr >> trueColorShift), true
+}
+
+// tcellColorFor resolves attr to a tcell.Color, decoding a packed true-color
+// RGB triple directly when omode is OutputTrue, and falling back to the
+// existing palette translation otherwise.
+func tcellColorFor(attr Attribute, omode OutputMode) tcell.Color {
+	if omode == OutputTrue {
+		if r, g, b, ok := trueColorRGB(attr); ok {
+			return tcell.NewRGBColor(int32(r), int32(g), int32(b))
+		}
+	}
+	return getTcellColor(attr, omode)
+}